@@ -0,0 +1,169 @@
+// Package backlog persists every inbound and outbound friend message in
+// a bbolt-backed store alongside the Tox savedata, and replays it on
+// request, matching the offline-delivery semantics described for
+// ToxFriendBacklog in the Syndicate Tox actor.
+package backlog
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DirectionIn is a message received from the friend.
+	DirectionIn = "in"
+	// DirectionOut is a message sent to the friend.
+	DirectionOut = "out"
+)
+
+var friendsBucket = []byte("friends")
+
+// Entry is one stored message.
+type Entry struct {
+	TS        time.Time
+	Direction string
+	Body      string
+	Delivered bool
+}
+
+type storedEntry struct {
+	TSNanos   int64  `json:"ts_ns"`
+	Direction string `json:"direction"`
+	Body      string `json:"body"`
+	Delivered bool   `json:"delivered"`
+}
+
+// Store is the on-disk message backlog, one bbolt sub-bucket per friend
+// public key.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) the backlog database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open backlog db: %w", err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(friendsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init backlog db: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func tsKey(ts time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(ts.UnixNano()))
+	return key
+}
+
+// Record appends one message to the friend's backlog.
+func (s *Store) Record(pubKey string, ts time.Time, direction, body string, delivered bool) error {
+	e := storedEntry{
+		TSNanos:   ts.UnixNano(),
+		Direction: direction,
+		Body:      body,
+		Delivered: delivered,
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		fb, err := tx.Bucket(friendsBucket).CreateBucketIfNotExists([]byte(pubKey))
+		if err != nil {
+			return err
+		}
+		return fb.Put(tsKey(ts), data)
+	})
+}
+
+// Range returns every message for pubKey with ts in [from, to], ordered
+// chronologically.
+func (s *Store) Range(pubKey string, from, to time.Time) ([]Entry, error) {
+	var out []Entry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		fb := tx.Bucket(friendsBucket).Bucket([]byte(pubKey))
+		if fb == nil {
+			return nil
+		}
+
+		c := fb.Cursor()
+		min := tsKey(from)
+		max := tsKey(to)
+		for k, v := c.Seek(min); k != nil && string(k) <= string(max); k, v = c.Next() {
+			var e storedEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			out = append(out, Entry{
+				TS:        time.Unix(0, e.TSNanos),
+				Direction: e.Direction,
+				Body:      e.Body,
+				Delivered: e.Delivered,
+			})
+		}
+		return nil
+	})
+	return out, err
+}
+
+// PendingOutbound returns outbound messages recorded while the friend
+// was offline, in chronological order.
+func (s *Store) PendingOutbound(pubKey string) ([]Entry, error) {
+	all, err := s.Range(pubKey, time.Unix(0, 0), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Entry
+	for _, e := range all {
+		if e.Direction == DirectionOut && !e.Delivered {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+// MarkDelivered flags the outbound message at ts as delivered.
+func (s *Store) MarkDelivered(pubKey string, ts time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		fb := tx.Bucket(friendsBucket).Bucket([]byte(pubKey))
+		if fb == nil {
+			return nil
+		}
+
+		key := tsKey(ts)
+		v := fb.Get(key)
+		if v == nil {
+			return nil
+		}
+
+		var e storedEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		e.Delivered = true
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return fb.Put(key, data)
+	})
+}
@@ -0,0 +1,86 @@
+package backlog
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "backlog.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestRangeOrdersChronologically(t *testing.T) {
+	s := openTestStore(t)
+	const pubKey = "friendkey"
+
+	base := time.Unix(1700000000, 0)
+	if err := s.Record(pubKey, base.Add(2*time.Second), DirectionIn, "second", true); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := s.Record(pubKey, base, DirectionOut, "first", true); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	entries, err := s.Range(pubKey, base.Add(-time.Hour), base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+	if entries[0].Body != "first" || entries[1].Body != "second" {
+		t.Errorf("entries = %#v, want first then second", entries)
+	}
+}
+
+func TestPendingOutboundSkipsDeliveredAndInbound(t *testing.T) {
+	s := openTestStore(t)
+	const pubKey = "friendkey"
+
+	base := time.Unix(1700000000, 0)
+	if err := s.Record(pubKey, base, DirectionOut, "delivered", true); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := s.Record(pubKey, base.Add(time.Second), DirectionOut, "pending", false); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := s.Record(pubKey, base.Add(2*time.Second), DirectionIn, "from friend", false); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	pending, err := s.PendingOutbound(pubKey)
+	if err != nil {
+		t.Fatalf("PendingOutbound: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Body != "pending" {
+		t.Errorf("PendingOutbound = %#v, want only the undelivered outbound entry", pending)
+	}
+}
+
+func TestMarkDelivered(t *testing.T) {
+	s := openTestStore(t)
+	const pubKey = "friendkey"
+	ts := time.Unix(1700000000, 0)
+
+	if err := s.Record(pubKey, ts, DirectionOut, "pending", false); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := s.MarkDelivered(pubKey, ts); err != nil {
+		t.Fatalf("MarkDelivered: %v", err)
+	}
+
+	pending, err := s.PendingOutbound(pubKey)
+	if err != nil {
+		t.Fatalf("PendingOutbound: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("PendingOutbound after MarkDelivered = %#v, want empty", pending)
+	}
+}
@@ -0,0 +1,275 @@
+// Package bootstrap manages the set of DHT bootstrap nodes the bot
+// connects through: parsing the configured node list, adding UDP and
+// TCP relays, and re-bootstrapping with backoff whenever the Tox
+// connection drops.
+package bootstrap
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Node is one bootstrap/relay candidate.
+type Node struct {
+	Host     string
+	UDPPort  uint16
+	TCPPorts []uint16
+	Key      string // hex public key
+}
+
+// Bootstrapper is the subset of *tox.Tox the manager needs; satisfied
+// by *tox.Tox, mocked in tests.
+type Bootstrapper interface {
+	Bootstrap(host string, port uint16, pubKey string) (bool, error)
+	AddTcpRelay(host string, port uint16, pubKey string) (bool, error)
+	SelfGetConnectionStatus() int
+}
+
+// ConnectionNone matches tox.CONNECTION_NONE without importing the
+// cgo-backed tox package into this unit.
+const ConnectionNone = 0
+
+// ParseEnv parses TOX_BOOTSTRAP_NODES entries of the form
+// "host:udpport:pubkey" or "host:udpport/tcpport1,tcpport2:pubkey".
+func ParseEnv(s string) []Node {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	var out []Node
+	var pending string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if pending != "" {
+			item = pending + "," + item
+			pending = ""
+		}
+		if item == "" {
+			continue
+		}
+		// A comma after "udpport/" separates TCP ports, not entries: keep
+		// joining pieces until the ":pubkey" field is reached.
+		if i := strings.Index(item, ":"); strings.Count(item, ":") == 1 && strings.Contains(item[i:], "/") {
+			pending = item
+			continue
+		}
+		if n, ok := parseNode(item); ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func parseNode(item string) (Node, bool) {
+	parts := strings.Split(item, ":")
+	if len(parts) < 3 {
+		return Node{}, false
+	}
+
+	host := strings.TrimSpace(parts[0])
+	portField := strings.TrimSpace(parts[1])
+	pubKey := strings.ReplaceAll(strings.TrimSpace(strings.Join(parts[2:], ":")), " ", "")
+
+	if _, err := hex.DecodeString(pubKey); err != nil {
+		return Node{}, false
+	}
+
+	udpStr, tcpStr, _ := strings.Cut(portField, "/")
+	udp64, err := strconv.ParseUint(udpStr, 10, 16)
+	if err != nil {
+		return Node{}, false
+	}
+
+	var tcpPorts []uint16
+	if tcpStr != "" {
+		for _, p := range strings.Split(tcpStr, ",") {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			p64, err := strconv.ParseUint(p, 10, 16)
+			if err != nil {
+				continue
+			}
+			tcpPorts = append(tcpPorts, uint16(p64))
+		}
+	}
+
+	return Node{Host: host, UDPPort: uint16(udp64), TCPPorts: tcpPorts, Key: pubKey}, true
+}
+
+// Defaults returns the built-in fallback nodes used when no env or
+// remote list is available.
+func Defaults() []Node {
+	return []Node{
+		{Host: "tox.abilinski.com", UDPPort: 33445, Key: "10C00EB250C3233E343E2AEBA07115A5C28920E9C8D29492F6D00B29049EDC7E"},
+		{Host: "144.217.167.73", UDPPort: 33445, Key: "7E5668E0EE09E19F320AD47902419331FFEE147BB3606769CFBE921A2A2FD34C"},
+	}
+}
+
+// Stats tracks bootstrap attempt outcomes for one node.
+type Stats struct {
+	Successes int64
+	Failures  int64
+}
+
+// Manager keeps the Tox connection bootstrapped: it tries every
+// configured node at startup, and whenever the connection is lost it
+// retries a rotating subset of nodes with exponential backoff.
+type Manager struct {
+	t     Bootstrapper
+	nodes []Node
+
+	mu       sync.Mutex
+	stats    map[string]*Stats
+	backoff  time.Duration
+	rotation int
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	checkEvery time.Duration
+}
+
+// NewManager builds a Manager over the given node list.
+func NewManager(t Bootstrapper, nodes []Node) *Manager {
+	return &Manager{
+		t:          t,
+		nodes:      nodes,
+		stats:      make(map[string]*Stats),
+		minBackoff: 2 * time.Second,
+		maxBackoff: 2 * time.Minute,
+		checkEvery: 5 * time.Second,
+	}
+}
+
+// SetNodes atomically swaps the node list, used after a refresh from
+// TOX_NODES_URL.
+func (m *Manager) SetNodes(nodes []Node) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes = nodes
+}
+
+// Stats returns a snapshot of per-node success/failure counters keyed
+// by "host:udpport".
+func (m *Manager) Stats() map[string]Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]Stats, len(m.stats))
+	for k, v := range m.stats {
+		out[k] = *v
+	}
+	return out
+}
+
+func nodeKey(n Node) string {
+	return n.Host + ":" + strconv.Itoa(int(n.UDPPort))
+}
+
+func (m *Manager) record(n Node, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.stats[nodeKey(n)]
+	if !exists {
+		s = &Stats{}
+		m.stats[nodeKey(n)] = s
+	}
+	if ok {
+		s.Successes++
+	} else {
+		s.Failures++
+	}
+}
+
+// connectAll bootstraps and adds a TCP relay for every configured node.
+func (m *Manager) connectAll() bool {
+	m.mu.Lock()
+	nodes := append([]Node(nil), m.nodes...)
+	m.mu.Unlock()
+
+	any := false
+	for _, n := range nodes {
+		ok, err := m.t.Bootstrap(n.Host, n.UDPPort, n.Key)
+		m.record(n, err == nil && ok)
+		any = any || (err == nil && ok)
+
+		for _, tcpPort := range n.TCPPorts {
+			_, _ = m.t.AddTcpRelay(n.Host, tcpPort, n.Key)
+		}
+	}
+	return any
+}
+
+// connectRotation re-bootstraps against a rotating subset of nodes,
+// advancing through the list on each call so repeated reconnect
+// attempts don't hammer the same node.
+func (m *Manager) connectRotation(size int) bool {
+	m.mu.Lock()
+	nodes := m.nodes
+	if len(nodes) == 0 {
+		m.mu.Unlock()
+		return false
+	}
+	if size <= 0 || size > len(nodes) {
+		size = len(nodes)
+	}
+	start := m.rotation % len(nodes)
+	m.rotation += size
+	m.mu.Unlock()
+
+	any := false
+	for i := 0; i < size; i++ {
+		n := nodes[(start+i)%len(nodes)]
+		ok, err := m.t.Bootstrap(n.Host, n.UDPPort, n.Key)
+		m.record(n, err == nil && ok)
+		any = any || (err == nil && ok)
+
+		for _, tcpPort := range n.TCPPorts {
+			_, _ = m.t.AddTcpRelay(n.Host, tcpPort, n.Key)
+		}
+	}
+	return any
+}
+
+// Run bootstraps immediately, then watches the connection status and
+// re-bootstraps with exponential backoff while disconnected. It blocks
+// until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context) {
+	m.connectAll()
+	m.backoff = m.minBackoff
+
+	ticker := time.NewTicker(m.checkEvery)
+	defer ticker.Stop()
+
+	var nextRetry time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.t.SelfGetConnectionStatus() != ConnectionNone {
+				m.backoff = m.minBackoff
+				nextRetry = time.Time{}
+				continue
+			}
+			if !nextRetry.IsZero() && time.Now().Before(nextRetry) {
+				continue
+			}
+
+			m.connectRotation(4)
+			nextRetry = time.Now().Add(m.backoff)
+			m.backoff *= 2
+			if m.backoff > m.maxBackoff {
+				m.backoff = m.maxBackoff
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package bootstrap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []Node
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: nil,
+		},
+		{
+			name: "udp only",
+			in:   "tox.example.com:33445:1234ABCD",
+			want: []Node{
+				{Host: "tox.example.com", UDPPort: 33445, Key: "1234ABCD"},
+			},
+		},
+		{
+			name: "udp and tcp ports",
+			in:   "tox.example.com:33445/3389,443:1234ABCD",
+			want: []Node{
+				{Host: "tox.example.com", UDPPort: 33445, TCPPorts: []uint16{3389, 443}, Key: "1234ABCD"},
+			},
+		},
+		{
+			name: "multiple entries, blanks skipped",
+			in:   "a.example.com:1:AB, , b.example.com:2:CD",
+			want: []Node{
+				{Host: "a.example.com", UDPPort: 1, Key: "AB"},
+				{Host: "b.example.com", UDPPort: 2, Key: "CD"},
+			},
+		},
+		{
+			name: "malformed entry skipped",
+			in:   "missing-fields, tox.example.com:33445:1234ABCD",
+			want: []Node{
+				{Host: "tox.example.com", UDPPort: 33445, Key: "1234ABCD"},
+			},
+		},
+		{
+			name: "non-hex key skipped",
+			in:   "tox.example.com:33445:not-hex",
+			want: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseEnv(tc.in)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseEnv(%q) = %#v, want %#v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
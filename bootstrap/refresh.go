@@ -0,0 +1,131 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// nodesFeed mirrors the subset of the nodes.tox.chat JSON feed this bot
+// cares about.
+type nodesFeed struct {
+	Nodes []feedNode `json:"nodes"`
+}
+
+type feedNode struct {
+	IPv4      string `json:"ipv4"`
+	Port      uint16 `json:"port"`
+	PublicKey string `json:"public_key"`
+	TCPPorts  []int  `json:"tcp_ports"`
+	StatusUDP bool   `json:"status_udp"`
+	StatusTCP bool   `json:"status_tcp"`
+}
+
+// FetchNodes downloads and parses a nodes.tox.chat-style feed, keeping
+// only nodes reporting a healthy UDP or TCP status.
+//
+// The feed carries no signature this package can verify, so url must be
+// an https:// URL: that at least rules out a plain on-path MITM
+// substituting a malicious node list over HTTP. If nodes.tox.chat's feed
+// gains a verifiable signature, prefer checking it here instead of
+// relying on TLS alone.
+func FetchNodes(ctx context.Context, url string) ([]Node, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("fetch nodes: refusing non-https nodes URL %q", url)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build nodes request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch nodes: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch nodes: unexpected status %s", resp.Status)
+	}
+
+	var feed nodesFeed
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("decode nodes feed: %w", err)
+	}
+
+	var out []Node
+	for _, fn := range feed.Nodes {
+		if !fn.StatusUDP && !fn.StatusTCP {
+			continue
+		}
+		if fn.IPv4 == "" || fn.PublicKey == "" {
+			continue
+		}
+
+		var tcpPorts []uint16
+		for _, p := range fn.TCPPorts {
+			if p > 0 && p <= 0xffff {
+				tcpPorts = append(tcpPorts, uint16(p))
+			}
+		}
+
+		out = append(out, Node{
+			Host:     fn.IPv4,
+			UDPPort:  fn.Port,
+			TCPPorts: tcpPorts,
+			Key:      fn.PublicKey,
+		})
+	}
+	return out, nil
+}
+
+// MergeNodes combines two node lists, preferring entries from extra
+// when both define the same host:port.
+func MergeNodes(base, extra []Node) []Node {
+	seen := make(map[string]bool, len(extra))
+	out := make([]Node, 0, len(base)+len(extra))
+
+	for _, n := range extra {
+		seen[nodeKey(n)] = true
+		out = append(out, n)
+	}
+	for _, n := range base {
+		if !seen[nodeKey(n)] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// RunRefresher periodically refetches the node list from url and
+// merges it into the manager, until ctx is cancelled.
+func RunRefresher(ctx context.Context, m *Manager, url string, every time.Duration, base []Node) {
+	if url == "" {
+		return
+	}
+
+	refresh := func() {
+		fetched, err := FetchNodes(ctx, url)
+		if err != nil {
+			return
+		}
+		m.SetNodes(MergeNodes(base, fetched))
+	}
+
+	refresh()
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
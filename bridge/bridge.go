@@ -0,0 +1,186 @@
+// Package bridge wires Tox friend and conference chat into one or more
+// external chat networks, modelled after matterbridge's Btox connector:
+// each remote gets its own flood-controlled outbound queue and inbound
+// messages are delivered through a single Remote channel.
+package bridge
+
+import (
+	"log"
+	"time"
+	"unicode/utf8"
+)
+
+// ToxMaxMessageLength mirrors TOX_MAX_MESSAGE_LENGTH from the Tox core
+// protocol; outbound messages longer than this are split on send.
+const ToxMaxMessageLength = 1372
+
+const (
+	defaultMessageDelay  = 1300 * time.Millisecond
+	defaultMessageQueue  = 30
+	defaultMessageLength = 400
+)
+
+// Message is a single chat line moving between Tox and a remote network.
+type Message struct {
+	// Channel identifies the Tox side: a friend public key for 1:1 chats
+	// or "conference:<title>" for group chats.
+	Channel  string
+	Username string
+	Text     string
+	// Remote is the name of the Bridge this message came from, empty for
+	// messages originating on the Tox side.
+	Remote string
+}
+
+// Bridge is one external chat network endpoint (IRC, XMPP, Matrix,
+// Telegram, ...). Implementations own their own connection lifecycle.
+type Bridge interface {
+	Name() string
+	Send(msg Message) error
+	// Remote delivers messages received from the external network; the
+	// channel is closed when the bridge shuts down.
+	Remote() chan Message
+}
+
+// RemoteConfig configures flood control for a single remote.
+type RemoteConfig struct {
+	MessageDelay  time.Duration
+	MessageQueue  int
+	MessageLength int
+}
+
+func (c RemoteConfig) withDefaults() RemoteConfig {
+	if c.MessageDelay <= 0 {
+		c.MessageDelay = defaultMessageDelay
+	}
+	if c.MessageQueue <= 0 {
+		c.MessageQueue = defaultMessageQueue
+	}
+	if c.MessageLength <= 0 {
+		c.MessageLength = defaultMessageLength
+	}
+	return c
+}
+
+// Fabric fans Tox messages out to configured bridges and pumps bridge
+// messages back into Tox via the Deliver callback.
+type Fabric struct {
+	Deliver func(channel string, text string)
+
+	remotes map[string]*remote
+}
+
+type remote struct {
+	b     Bridge
+	cfg   RemoteConfig
+	queue chan Message
+}
+
+// NewFabric builds an empty fabric; call Add for each configured remote.
+func NewFabric(deliver func(channel string, text string)) *Fabric {
+	return &Fabric{
+		Deliver: deliver,
+		remotes: make(map[string]*remote),
+	}
+}
+
+// Add registers a bridge and starts pumping its inbound Remote channel
+// into the fabric, and its outbound queue out to the bridge at the
+// configured rate.
+func (f *Fabric) Add(b Bridge, cfg RemoteConfig) {
+	cfg = cfg.withDefaults()
+	r := &remote{
+		b:     b,
+		cfg:   cfg,
+		queue: make(chan Message, cfg.MessageQueue),
+	}
+	f.remotes[b.Name()] = r
+
+	go f.pumpOutbound(r)
+	go f.pumpInbound(r)
+}
+
+func (f *Fabric) pumpOutbound(r *remote) {
+	ticker := time.NewTicker(r.cfg.MessageDelay)
+	defer ticker.Stop()
+
+	for msg := range r.queue {
+		for _, chunk := range SplitMessage(msg.Text, r.cfg.MessageLength) {
+			m := msg
+			m.Text = chunk
+			if err := r.b.Send(m); err != nil {
+				log.Printf("bridge %s: send failed: %v", r.b.Name(), err)
+			}
+			<-ticker.C
+		}
+	}
+}
+
+func (f *Fabric) pumpInbound(r *remote) {
+	for msg := range r.b.Remote() {
+		if f.Deliver == nil {
+			continue
+		}
+		msg.Remote = r.b.Name()
+		for _, chunk := range SplitMessage(msg.Text, ToxMaxMessageLength) {
+			f.Deliver(msg.Channel, chunk)
+		}
+	}
+}
+
+// Broadcast queues a Tox-originated message for every registered remote
+// except the one it came from (to avoid echoing it back).
+func (f *Fabric) Broadcast(msg Message) {
+	for name, r := range f.remotes {
+		if name == msg.Remote {
+			continue
+		}
+		select {
+		case r.queue <- msg:
+		default:
+			log.Printf("bridge %s: queue full, dropping message", name)
+		}
+	}
+}
+
+// SplitMessage breaks text into chunks no longer than maxLen bytes,
+// preferring to split on whitespace. maxLen is bytes, not runes, to
+// match the wire limit FriendSendMessage enforces (ToxMaxMessageLength);
+// when no whitespace is available to split on, the cut point is backed
+// up to the nearest rune boundary so no chunk ends mid-codepoint and
+// becomes invalid UTF-8.
+func SplitMessage(text string, maxLen int) []string {
+	if maxLen <= 0 || len(text) <= maxLen {
+		return []string{text}
+	}
+
+	var chunks []string
+	for len(text) > maxLen {
+		cut := maxLen
+		if idx := lastSpace(text[:maxLen]); idx > 0 {
+			cut = idx
+		} else {
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+		for len(text) > 0 && text[0] == ' ' {
+			text = text[1:]
+		}
+	}
+	if len(text) > 0 {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+func lastSpace(s string) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,69 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessageUnderLimit(t *testing.T) {
+	got := SplitMessage("hello world", 100)
+	want := []string{"hello world"}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("SplitMessage = %#v, want %#v", got, want)
+	}
+}
+
+func TestSplitMessageSplitsOnSpace(t *testing.T) {
+	got := SplitMessage("one two three four", 11)
+	want := []string{"one two", "three four"}
+	if len(got) != len(want) {
+		t.Fatalf("SplitMessage = %#v, want %#v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	for i, chunk := range got {
+		if len(chunk) > 11 {
+			t.Errorf("chunk %d %q exceeds maxLen", i, chunk)
+		}
+	}
+}
+
+func TestSplitMessageHardCutWithNoSpace(t *testing.T) {
+	text := strings.Repeat("a", 25)
+	got := SplitMessage(text, 10)
+	if len(got) != 3 {
+		t.Fatalf("SplitMessage = %#v, want 3 chunks", got)
+	}
+	if strings.Join(got, "") != text {
+		t.Errorf("rejoined chunks = %q, want %q", strings.Join(got, ""), text)
+	}
+}
+
+func TestSplitMessageHardCutKeepsValidUTF8(t *testing.T) {
+	// "€" is 3 bytes; a maxLen of 10 lands mid-rune with a byte-offset cut.
+	text := strings.Repeat("€", 10)
+	got := SplitMessage(text, 10)
+
+	for i, chunk := range got {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %d %q is not valid UTF-8", i, chunk)
+		}
+		if len(chunk) > 10 {
+			t.Errorf("chunk %d %q exceeds maxLen", i, chunk)
+		}
+	}
+	if strings.Join(got, "") != text {
+		t.Errorf("rejoined chunks = %q, want %q", strings.Join(got, ""), text)
+	}
+}
+
+func TestSplitMessageZeroMaxLen(t *testing.T) {
+	got := SplitMessage("hello", 0)
+	if len(got) != 1 || got[0] != "hello" {
+		t.Errorf("SplitMessage with maxLen=0 = %#v, want unsplit input", got)
+	}
+}
@@ -0,0 +1,170 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config is the shape of the file pointed to by TOX_BRIDGE_CONFIG, one
+// [[remotes]] table per configured remote, matterbridge-gateway style:
+//
+//	[[remotes]]
+//	name = "tox-irc"
+//	type = "irc"
+//	tox_channel = "conference:general"
+//	server = "irc.example.net:6667"
+//	nick = "toxbot"
+//	channel = "#general"
+//	message_delay_ms = 500
+//	message_queue = 50
+//	message_length = 400
+type Config struct {
+	Remotes []RemoteDef
+}
+
+// RemoteDef names one configured remote and maps it to a Tox channel
+// (friend public key or "conference:<title>" for group chats).
+//
+// Type selects the connector: "irc" is currently the only one built
+// in (Server/Nick/Channel below configure it). XMPP/Matrix/Telegram
+// remotes can be added the same way as connectors are implemented.
+type RemoteDef struct {
+	Name          string
+	Type          string
+	ToxChannel    string
+	MessageDelay  int
+	MessageQueue  int
+	MessageLength int
+
+	// IRC connector settings.
+	Server  string
+	Nick    string
+	Channel string
+}
+
+// LoadConfig reads and parses a bridge config file.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read bridge config: %w", err)
+	}
+
+	cfg, err := parseConfig(b)
+	if err != nil {
+		return cfg, fmt.Errorf("parse bridge config: %w", err)
+	}
+	return cfg, nil
+}
+
+// parseConfig reads the small TOML subset LoadConfig needs: [[remotes]]
+// array-of-tables sections containing "key = value" pairs, string
+// values double-quoted and integer values bare. This is not a general
+// TOML parser - it covers exactly the fixed shape above, in exchange for
+// not pulling in a TOML dependency.
+func parseConfig(data []byte) (Config, error) {
+	var cfg Config
+	var cur *RemoteDef
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if line == "[[remotes]]" {
+			if cur != nil {
+				cfg.Remotes = append(cfg.Remotes, *cur)
+			}
+			cur = &RemoteDef{}
+			continue
+		}
+
+		if cur == nil {
+			return cfg, fmt.Errorf("line %d: %q outside any [[remotes]] table", lineNum, line)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return cfg, fmt.Errorf("line %d: expected key = value, got %q", lineNum, line)
+		}
+		if err := setField(cur, strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return cfg, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+	if cur != nil {
+		cfg.Remotes = append(cfg.Remotes, *cur)
+	}
+	return cfg, nil
+}
+
+func setField(r *RemoteDef, key, value string) error {
+	switch key {
+	case "name", "type", "tox_channel", "server", "nick", "channel":
+		s, err := unquote(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		switch key {
+		case "name":
+			r.Name = s
+		case "type":
+			r.Type = s
+		case "tox_channel":
+			r.ToxChannel = s
+		case "server":
+			r.Server = s
+		case "nick":
+			r.Nick = s
+		case "channel":
+			r.Channel = s
+		}
+	case "message_delay_ms", "message_queue", "message_length":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: expected an integer, got %q", key, value)
+		}
+		switch key {
+		case "message_delay_ms":
+			r.MessageDelay = n
+		case "message_queue":
+			r.MessageQueue = n
+		case "message_length":
+			r.MessageLength = n
+		}
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+func unquote(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string, got %q", value)
+	}
+	return value[1 : len(value)-1], nil
+}
+
+// stripComment removes a trailing "# ..." comment, ignoring '#' inside a
+// quoted string.
+func stripComment(line string) string {
+	inQuotes := false
+	for i, r := range line {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case '#':
+			if !inQuotes {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,81 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	data := []byte(`
+# comment before any table should error if it has content, blank/comment-only is fine
+
+[[remotes]]
+name = "tox-irc"
+type = "irc"
+tox_channel = "conference:general" # inline comment
+server = "irc.example.net:6667"
+nick = "toxbot"
+channel = "#general"
+message_delay_ms = 500
+message_queue = 50
+message_length = 400
+
+[[remotes]]
+name = "tox-irc-2"
+type = "irc"
+tox_channel = "deadbeef"
+server = "irc2.example.net:6667"
+nick = "toxbot2"
+channel = "#other"
+`)
+
+	cfg, err := parseConfig(data)
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	if len(cfg.Remotes) != 2 {
+		t.Fatalf("len(Remotes) = %d, want 2", len(cfg.Remotes))
+	}
+
+	want := RemoteDef{
+		Name:          "tox-irc",
+		Type:          "irc",
+		ToxChannel:    "conference:general",
+		Server:        "irc.example.net:6667",
+		Nick:          "toxbot",
+		Channel:       "#general",
+		MessageDelay:  500,
+		MessageQueue:  50,
+		MessageLength: 400,
+	}
+	if cfg.Remotes[0] != want {
+		t.Errorf("Remotes[0] = %#v, want %#v", cfg.Remotes[0], want)
+	}
+	if cfg.Remotes[1].Name != "tox-irc-2" || cfg.Remotes[1].ToxChannel != "deadbeef" {
+		t.Errorf("Remotes[1] = %#v", cfg.Remotes[1])
+	}
+}
+
+func TestParseConfigKeyOutsideTable(t *testing.T) {
+	_, err := parseConfig([]byte(`name = "orphan"`))
+	if err == nil || !strings.Contains(err.Error(), "outside any") {
+		t.Errorf("err = %v, want an 'outside any [[remotes]] table' error", err)
+	}
+}
+
+func TestLoadConfigReadsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge.toml")
+	if err := os.WriteFile(path, []byte("[[remotes]]\nname = \"x\"\ntype = \"irc\"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Remotes) != 1 || cfg.Remotes[0].Name != "x" {
+		t.Errorf("Remotes = %#v", cfg.Remotes)
+	}
+}
@@ -0,0 +1,114 @@
+package bridge
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IRCConfig configures one IRC remote.
+type IRCConfig struct {
+	Name       string // bridge name, used for routing/dedup
+	Server     string // host:port
+	Nick       string
+	Channel    string // IRC channel to join, e.g. "#tox"
+	ToxChannel string // friend pubkey or "conference:<title>" this IRC channel maps to
+}
+
+// IRCBridge relays messages between one IRC channel and one Tox
+// friend/conference, using raw PRIVMSG lines (no SASL/TLS) as the
+// minimal transport matterbridge's Btox connector expects a Bridge to
+// provide.
+type IRCBridge struct {
+	cfg    IRCConfig
+	conn   net.Conn
+	remote chan Message
+}
+
+// DialIRC connects to cfg.Server, registers as cfg.Nick and joins
+// cfg.Channel, then starts relaying PRIVMSGs from that channel into the
+// returned bridge's Remote channel.
+func DialIRC(cfg IRCConfig) (*IRCBridge, error) {
+	conn, err := net.Dial("tcp", cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("dial irc %s: %w", cfg.Server, err)
+	}
+
+	b := &IRCBridge{
+		cfg:    cfg,
+		conn:   conn,
+		remote: make(chan Message, defaultMessageQueue),
+	}
+
+	fmt.Fprintf(conn, "NICK %s\r\n", cfg.Nick)
+	fmt.Fprintf(conn, "USER %s 0 * :%s\r\n", cfg.Nick, cfg.Nick)
+	fmt.Fprintf(conn, "JOIN %s\r\n", cfg.Channel)
+
+	go b.readLoop()
+	return b, nil
+}
+
+func (b *IRCBridge) Name() string { return b.cfg.Name }
+
+// Remote delivers messages received from IRC, tagged with the Tox
+// channel this bridge is mapped to.
+func (b *IRCBridge) Remote() chan Message { return b.remote }
+
+// Send writes msg.Text to the IRC channel as a PRIVMSG. Messages from
+// any Tox channel other than cfg.ToxChannel are dropped.
+func (b *IRCBridge) Send(msg Message) error {
+	if msg.Channel != b.cfg.ToxChannel {
+		return nil
+	}
+	_, err := fmt.Fprintf(b.conn, "PRIVMSG %s :%s\r\n", b.cfg.Channel, msg.Text)
+	return err
+}
+
+func (b *IRCBridge) readLoop() {
+	defer close(b.remote)
+
+	scanner := bufio.NewScanner(b.conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "PING ") {
+			fmt.Fprintf(b.conn, "PONG %s\r\n", strings.TrimPrefix(line, "PING "))
+			continue
+		}
+
+		nick, text, ok := parsePrivmsg(line)
+		if !ok {
+			continue
+		}
+
+		b.remote <- Message{
+			Channel:  b.cfg.ToxChannel,
+			Username: nick,
+			Text:     text,
+			Remote:   b.cfg.Name,
+		}
+	}
+}
+
+// parsePrivmsg extracts the sender nick and text from a raw
+// ":nick!user@host PRIVMSG #channel :text" line.
+func parsePrivmsg(line string) (nick, text string, ok bool) {
+	if !strings.HasPrefix(line, ":") {
+		return "", "", false
+	}
+	prefix, rest, found := strings.Cut(line[1:], " ")
+	if !found {
+		return "", "", false
+	}
+	nick, _, _ = strings.Cut(prefix, "!")
+
+	if !strings.Contains(rest, "PRIVMSG") {
+		return "", "", false
+	}
+	_, msgPart, found := strings.Cut(rest, " :")
+	if !found {
+		return "", "", false
+	}
+	return nick, msgPart, true
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -13,71 +13,21 @@ import (
 	"time"
 
 	tox "github.com/TokTok/go-toxcore-c"
+
+	"github.com/nexusriot/toxcbt/backlog"
+	"github.com/nexusriot/toxcbt/bootstrap"
+	"github.com/nexusriot/toxcbt/bridge"
+	"github.com/nexusriot/toxcbt/conference"
+	"github.com/nexusriot/toxcbt/control"
+	"github.com/nexusriot/toxcbt/inbox"
 )
 
 const (
 	defaultName   = "go-tox-bot"
 	defaultStatus = "echo bot"
-)
-
-type bootstrapNode struct {
-	host string
-	port uint16
-	key  string // hex public key
-}
-
-// TOX_BOOTSTRAP_NODES format:
-// host:port:pubkeyhex,host:port:pubkeyhex,...
-func parseBootstrapEnv(s string) []bootstrapNode {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return nil
-	}
-
-	var out []bootstrapNode
-	for _, item := range strings.Split(s, ",") {
-		item = strings.TrimSpace(item)
-		if item == "" {
-			continue
-		}
-		parts := strings.Split(item, ":")
-		if len(parts) < 3 {
-			log.Printf("bootstrap entry skipped (need host:port:pubkey): %q", item)
-			continue
-		}
-
-		host := strings.TrimSpace(parts[0])
-		portStr := strings.TrimSpace(parts[1])
-		pubKey := strings.TrimSpace(strings.Join(parts[2:], ":"))
-
-		p64, err := strconv.ParseUint(portStr, 10, 16)
-		if err != nil {
-			log.Printf("bootstrap entry skipped (bad port) %q: %v", item, err)
-			continue
-		}
-
-		// validate key looks like hex
-		pubKey = strings.ReplaceAll(pubKey, " ", "")
-		if _, err := hex.DecodeString(pubKey); err != nil {
-			log.Printf("bootstrap entry skipped (bad pubkey hex) %q: %v", item, err)
-			continue
-		}
-
-		out = append(out, bootstrapNode{
-			host: host,
-			port: uint16(p64),
-			key:  pubKey,
-		})
-	}
-	return out
-}
 
-func defaultBootstrap() []bootstrapNode {
-	return []bootstrapNode{
-		{"tox.abilinski.com", 33445, "10C00EB250C3233E343E2AEBA07115A5C28920E9C8D29492F6D00B29049EDC7E"},
-		{"144.217.167.73", 33445, "7E5668E0EE09E19F320AD47902419331FFEE147BB3606769CFBE921A2A2FD34C"},
-	}
-}
+	nodesRefreshInterval = 30 * time.Minute
+)
 
 func getenv(key, def string) string {
 	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
@@ -121,24 +71,247 @@ func main() {
 	log.Printf("Tox ID: %s", t.SelfGetAddress())
 	log.Printf("Public Key: %s", t.SelfGetPublicKey())
 
-	// Bootstrap nodes
-	nodes := parseBootstrapEnv(os.Getenv("TOX_BOOTSTRAP_NODES"))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// libtoxcore is not thread-safe: t.Iterate() and every *tox.Tox call
+	// must happen on this goroutine. Other goroutines (the control
+	// socket, the bootstrap manager, the bridge fabric) call into Tox by
+	// sending a closure here and blocking until the main loop below has
+	// run it.
+	toxQueue := make(chan func())
+	runOnTox := func(fn func()) {
+		done := make(chan struct{})
+		toxQueue <- func() { fn(); close(done) }
+		<-done
+	}
+
+	// Bootstrap nodes: a manager goroutine keeps the connection alive,
+	// re-bootstrapping against a rotating subset of nodes with backoff
+	// whenever SelfGetConnectionStatus drops, and also adds TCP relays
+	// so the bot still connects behind restrictive NATs.
+	nodes := bootstrap.ParseEnv(os.Getenv("TOX_BOOTSTRAP_NODES"))
 	if len(nodes) == 0 {
-		nodes = defaultBootstrap()
+		nodes = bootstrap.Defaults()
 		log.Printf("TOX_BOOTSTRAP_NODES empty; using %d default nodes", len(nodes))
 	} else {
 		log.Printf("using %d nodes from TOX_BOOTSTRAP_NODES", len(nodes))
 	}
 
-	for _, n := range nodes {
-		ok, err := t.Bootstrap(n.host, n.port, n.key)
-		if err != nil || !ok {
-			log.Printf("bootstrap failed %s:%d: ok=%v err=%v", n.host, n.port, ok, err)
+	// bsMgr.Run ticks on its own goroutine, so its calls into Tox go
+	// through &syncTox{} below rather than the real *tox.Tox, keeping
+	// them marshaled onto the iterate goroutine.
+	bsMgr := bootstrap.NewManager(&syncTox{t: t, run: runOnTox}, nodes)
+	go bsMgr.Run(ctx)
+
+	if nodesURL := os.Getenv("TOX_NODES_URL"); nodesURL != "" {
+		go bootstrap.RunRefresher(ctx, bsMgr, nodesURL, nodesRefreshInterval, nodes)
+	}
+
+	// Message backlog: persists incoming friend messages and the messages
+	// sent through reply and sendToFriend, replays them on /backlog, and
+	// resends undelivered outbound ones once an offline friend reconnects.
+	// The output of /backlog, /ls, /get, /groups and /invite is not recorded.
+	var bl *backlog.Store
+	msgDB := getenv("TOX_MSG_DB", filepath.Join(dataDir, "messages.db"))
+	if b, err := backlog.Open(msgDB); err != nil {
+		log.Printf("backlog not available: %v", err)
+	} else {
+		bl = b
+		defer bl.Close()
+	}
+
+	// Control dataspace: publishes Tox state and accepts commands from
+	// local agents over a Unix socket configured via TOX_CONTROL_SOCK.
+	var ctl *control.Server
+	if sockPath := os.Getenv("TOX_CONTROL_SOCK"); sockPath != "" {
+		// Each handler runs on the control socket's readLoop goroutine, so
+		// every Tox call is marshaled onto the iterate goroutine via
+		// runOnTox rather than touching t directly here.
+		s, err := control.Listen(sockPath, control.Handlers{
+			OnFriendAdd: func(cmd control.FriendAddCmd) error {
+				var err error
+				runOnTox(func() { _, err = t.FriendAdd(cmd.Address, cmd.Message) })
+				return err
+			},
+			OnFriendSend: func(cmd control.FriendSendCmd) error {
+				return sendToFriend(t, bl, runOnTox, cmd.Num, cmd.Message)
+			},
+			OnSelfSetName: func(cmd control.SelfSetNameCmd) error {
+				var err error
+				runOnTox(func() { err = t.SelfSetName(cmd.Name) })
+				return err
+			},
+			OnSelfSetStatus: func(cmd control.SelfSetStatusCmd) error {
+				var err error
+				runOnTox(func() { _, err = t.SelfSetStatusMessage(cmd.Status) })
+				return err
+			},
+		})
+		if err != nil {
+			log.Printf("control socket not started: %v", err)
+		} else {
+			ctl = s
+			defer ctl.Close()
+			ctl.Publish(control.TypeSelfAddress, map[string]string{"address": t.SelfGetAddress()})
+			log.Printf("control socket listening on %s", sockPath)
+		}
+	}
+
+	// File transfer inbox: accepts incoming files into a per-friend
+	// directory and serves them back out via /get and /ls.
+	var maxInboxBytes int64
+	if v := os.Getenv("TOX_INBOX_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			maxInboxBytes = n
+		} else {
+			log.Printf("TOX_INBOX_MAX_BYTES invalid, ignoring: %v", err)
+		}
+	}
+	ib := inbox.NewManager(filepath.Join(dataDir, "inbox"), maxInboxBytes)
+
+	// Conferences: auto-join invites, optionally gated by TOX_GROUP_ADMINS,
+	// and echo messages addressed to the bot by name.
+	var groupAdmins []string
+	if v := os.Getenv("TOX_GROUP_ADMINS"); v != "" {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				groupAdmins = append(groupAdmins, k)
+			}
+		}
+	}
+	confSidecar := saveFile + ".conferences.json"
+	conf := conference.NewManager(t, groupAdmins, confSidecar, name)
+	if err := conf.Load(); err != nil {
+		log.Printf("conference sidecar not loaded: %v", err)
+	}
+
+	// Bridge fabric: relays friend and conference messages to/from
+	// external chat networks configured via TOX_BRIDGE_CONFIG. Deliver
+	// runs on the fabric's own pump goroutine, so every Tox call it
+	// makes is marshaled onto the iterate goroutine via runOnTox.
+	var fab *bridge.Fabric
+	if cfgPath := os.Getenv("TOX_BRIDGE_CONFIG"); cfgPath != "" {
+		cfg, err := bridge.LoadConfig(cfgPath)
+		if err != nil {
+			log.Printf("bridge config not loaded: %v", err)
 		} else {
-			log.Printf("bootstrapped %s:%d", n.host, n.port)
+			fab = bridge.NewFabric(func(channel string, text string) {
+				if groupName, ok := strings.CutPrefix(channel, "conference:"); ok {
+					var sendErr error
+					runOnTox(func() { sendErr = conf.SendByTitle(groupName, text) })
+					if sendErr != nil {
+						log.Printf("bridge deliver: %v", sendErr)
+					}
+					return
+				}
+
+				var fn uint32
+				var err error
+				runOnTox(func() { fn, err = t.FriendByPublicKey(channel) })
+				if err != nil {
+					log.Printf("bridge deliver: unknown friend %s: %v", channel, err)
+					return
+				}
+				if sendErr := sendToFriend(t, bl, runOnTox, fn, text); sendErr != nil {
+					log.Printf("bridge deliver: send to %s failed: %v", channel, sendErr)
+				}
+			})
+
+			for _, r := range cfg.Remotes {
+				remoteCfg := bridge.RemoteConfig{
+					MessageDelay:  time.Duration(r.MessageDelay) * time.Millisecond,
+					MessageQueue:  r.MessageQueue,
+					MessageLength: r.MessageLength,
+				}
+				switch r.Type {
+				case "irc":
+					b, err := bridge.DialIRC(bridge.IRCConfig{
+						Name:       r.Name,
+						Server:     r.Server,
+						Nick:       r.Nick,
+						Channel:    r.Channel,
+						ToxChannel: r.ToxChannel,
+					})
+					if err != nil {
+						log.Printf("bridge remote %q not started: %v", r.Name, err)
+						continue
+					}
+					fab.Add(b, remoteCfg)
+					log.Printf("bridge remote %q (irc) connected, mapped to %s", r.Name, r.ToxChannel)
+				default:
+					log.Printf("bridge remote %q skipped: unknown type %q", r.Name, r.Type)
+				}
+			}
 		}
 	}
 
+	t.CallbackConferenceInviteAdd(func(_ *tox.Tox, friend uint32, _ int, cookie []byte, _ interface{}) {
+		pubKey, err := t.FriendGetPublicKey(friend)
+		if err != nil || !conf.AllowInvite(pubKey) {
+			log.Printf("conference invite from %d rejected", friend)
+			return
+		}
+		num, err := conf.HandleInvite(friend, pubKey, cookie)
+		if err != nil {
+			log.Printf("conference join failed: %v", err)
+			return
+		}
+		log.Printf("joined conference #%d (invited by %d)", num, friend)
+	}, nil)
+
+	t.CallbackConferenceMessageAdd(func(_ *tox.Tox, group uint32, _ uint32, _ int, message string, _ interface{}) {
+		conf.HandleMessage(group, message)
+
+		if fab != nil {
+			if title, err := t.ConferenceGetTitle(group); err == nil {
+				fab.Broadcast(bridge.Message{Channel: "conference:" + title, Text: message})
+			}
+		}
+	}, nil)
+
+	t.CallbackFileRecvAdd(func(_ *tox.Tox, friend uint32, fileNum uint32, kind uint32, fileSize uint64, fileName string, _ interface{}) {
+		if kind != tox.FILE_KIND_DATA {
+			_ = t.FileControl(friend, fileNum, tox.FILE_CONTROL_CANCEL)
+			return
+		}
+
+		pubKey, err := t.FriendGetPublicKey(friend)
+		if err != nil {
+			_ = t.FileControl(friend, fileNum, tox.FILE_CONTROL_CANCEL)
+			return
+		}
+
+		resumeAt, err := ib.Accept(friend, fileNum, pubKey, fileSize, fileName)
+		if err != nil {
+			log.Printf("file transfer rejected from %d (%s): %v", friend, fileName, err)
+			_ = t.FileControl(friend, fileNum, tox.FILE_CONTROL_CANCEL)
+			return
+		}
+		if resumeAt > 0 {
+			_ = t.FileSeek(friend, fileNum, resumeAt)
+		}
+		_ = t.FileControl(friend, fileNum, tox.FILE_CONTROL_RESUME)
+		log.Printf("accepting file %q from %d (%d bytes, resume at %d)", fileName, friend, fileSize, resumeAt)
+	}, nil)
+
+	t.CallbackFileRecvChunkAdd(func(_ *tox.Tox, friend uint32, fileNum uint32, position uint64, data []byte, _ interface{}) {
+		if err := ib.WriteChunk(friend, fileNum, position, data); err != nil {
+			log.Printf("file chunk write failed: %v", err)
+			ib.AbortRecv(friend, fileNum)
+			_ = t.FileControl(friend, fileNum, tox.FILE_CONTROL_CANCEL)
+		}
+	}, nil)
+
+	t.CallbackFileChunkRequestAdd(func(_ *tox.Tox, friend uint32, fileNum uint32, position uint64, length int, _ interface{}) {
+		chunk, err := ib.ReadChunk(friend, fileNum, position, length)
+		if err != nil {
+			log.Printf("file chunk read failed: %v", err)
+			return
+		}
+		_ = t.FileSendChunk(friend, fileNum, position, chunk)
+	}, nil)
+
 	// Auto-accept friend requests
 	t.CallbackFriendRequestAdd(func(_ *tox.Tox, pubKey string, msg string, _ interface{}) {
 		log.Printf("friend request from %s msg=%q", pubKey, msg)
@@ -148,6 +321,15 @@ func main() {
 			return
 		}
 		log.Printf("friend accepted: #%d", fn)
+		if ctl != nil {
+			ctl.Publish(control.TypeFriendKey, map[string]interface{}{"num": fn, "key": pubKey})
+		}
+	}, nil)
+
+	t.CallbackSelfConnectionStatusAdd(func(_ *tox.Tox, status int, _ interface{}) {
+		if ctl != nil {
+			ctl.Publish(control.TypeSelfConnectionStatus, map[string]int{"status": status})
+		}
 	}, nil)
 
 	// In v0.2.17 the friend-message callback type does NOT include mtype.
@@ -156,18 +338,100 @@ func main() {
 		msg := strings.TrimSpace(message)
 		log.Printf("msg from %d: %q", friend, msg)
 
-		switch msg {
-		case "/ping":
-			_, _ = t.FriendSendMessage(friend, "pong")
-		case "/id":
-			_, _ = t.FriendSendMessage(friend, "my tox id: "+t.SelfGetAddress())
+		pubKey, pkErr := t.FriendGetPublicKey(friend)
+		if bl != nil && pkErr == nil {
+			if err := bl.Record(pubKey, time.Now(), backlog.DirectionIn, message, true); err != nil {
+				log.Printf("backlog record failed: %v", err)
+			}
+		}
+
+		switch {
+		case msg == "/ping":
+			reply(t, bl, friend, pubKey, "pong")
+		case msg == "/id":
+			reply(t, bl, friend, pubKey, "my tox id: "+t.SelfGetAddress())
+		case strings.HasPrefix(msg, "/backlog"):
+			replayBacklog(t, bl, friend, pubKey, msg)
+		case msg == "/ls":
+			listInbox(t, ib, friend, pubKey)
+		case strings.HasPrefix(msg, "/get "):
+			sendFromInbox(t, ib, friend, pubKey, strings.TrimPrefix(msg, "/get "))
+		case msg == "/groups":
+			listGroups(t, conf, friend)
+		case strings.HasPrefix(msg, "/invite "):
+			inviteToGroup(t, conf, friend, strings.TrimPrefix(msg, "/invite "))
 		default:
-			_, _ = t.FriendSendMessage(friend, "echo: "+message)
+			reply(t, bl, friend, pubKey, "echo: "+message)
+		}
+
+		if fab != nil && pkErr == nil {
+			fab.Broadcast(bridge.Message{Channel: pubKey, Text: message})
 		}
 	}, nil)
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+	t.CallbackFriendNameAdd(func(_ *tox.Tox, friend uint32, name string, _ interface{}) {
+		if ctl != nil {
+			ctl.Publish(control.TypeFriendName, map[string]interface{}{"num": friend, "name": name})
+		}
+	}, nil)
+
+	t.CallbackFriendStatusMessageAdd(func(_ *tox.Tox, friend uint32, status string, _ interface{}) {
+		if ctl != nil {
+			ctl.Publish(control.TypeFriendStatusMessage, map[string]interface{}{"num": friend, "status": status})
+		}
+	}, nil)
+
+	t.CallbackFriendTypingAdd(func(_ *tox.Tox, friend uint32, typing bool, _ interface{}) {
+		if ctl != nil {
+			ctl.Publish(control.TypeFriendTyping, map[string]interface{}{"num": friend, "typing": typing})
+		}
+	}, nil)
+
+	t.CallbackFriendConnectionStatusAdd(func(_ *tox.Tox, friend uint32, status int, _ interface{}) {
+		if status == tox.CONNECTION_NONE {
+			if ctl != nil {
+				ctl.Publish(control.TypeFriendLastOnline, map[string]interface{}{
+					"num": friend,
+					"ts":  time.Now().Unix(),
+				})
+			}
+			return
+		}
+
+		pubKey, err := t.FriendGetPublicKey(friend)
+		if err != nil {
+			return
+		}
+
+		for _, rejoinErr := range conf.RejoinFriend(friend, pubKey) {
+			log.Printf("conference rejoin failed: %v", rejoinErr)
+		}
+
+		if bl == nil {
+			return
+		}
+		pending, err := bl.PendingOutbound(pubKey)
+		if err != nil {
+			log.Printf("backlog pending lookup failed: %v", err)
+			return
+		}
+		for _, e := range pending {
+			sent := true
+			for _, chunk := range bridge.SplitMessage(e.Body, bridge.ToxMaxMessageLength) {
+				if _, err := t.FriendSendMessage(friend, chunk); err != nil {
+					log.Printf("backlog redeliver failed: %v", err)
+					sent = false
+					continue
+				}
+			}
+			if !sent {
+				continue
+			}
+			if err := bl.MarkDelivered(pubKey, e.TS); err != nil {
+				log.Printf("backlog mark delivered failed: %v", err)
+			}
+		}
+	}, nil)
 
 	saveTick := time.NewTicker(30 * time.Second)
 	defer saveTick.Stop()
@@ -184,6 +448,9 @@ func main() {
 		case <-saveTick.C:
 			save(t, saveFile)
 
+		case fn := <-toxQueue:
+			fn()
+
 		default:
 			t.Iterate()
 			time.Sleep(time.Duration(t.IterationInterval()) * time.Millisecond)
@@ -211,3 +478,201 @@ func save(t *tox.Tox, path string) {
 	}
 	log.Printf("saved: %s (%d bytes)", path, len(data))
 }
+
+// reply sends text to friend and, if a backlog store is configured,
+// records it as an outbound message, marked delivered only if
+// FriendSendMessage succeeded.
+func reply(t *tox.Tox, bl *backlog.Store, friend uint32, pubKey string, text string) {
+	_, err := t.FriendSendMessage(friend, text)
+	if bl != nil && pubKey != "" {
+		if recErr := bl.Record(pubKey, time.Now(), backlog.DirectionOut, text, err == nil); recErr != nil {
+			log.Printf("backlog record failed: %v", recErr)
+		}
+	}
+}
+
+// sendToFriend sends text to friend from a goroutine other than the
+// main loop (control handlers, bridge delivery), marshaling the Tox
+// calls through runOnTox. Unlike reply, the friend may be offline, so
+// the backlog is recorded as undelivered until the send actually
+// succeeds, giving the reconnect handler something to flush.
+func sendToFriend(t *tox.Tox, bl *backlog.Store, runOnTox func(func()), friend uint32, text string) error {
+	var pubKey string
+	var err error
+	runOnTox(func() {
+		pubKey, _ = t.FriendGetPublicKey(friend)
+		_, err = t.FriendSendMessage(friend, text)
+	})
+	if bl != nil && pubKey != "" {
+		if recErr := bl.Record(pubKey, time.Now(), backlog.DirectionOut, text, err == nil); recErr != nil {
+			log.Printf("backlog record failed: %v", recErr)
+		}
+	}
+	return err
+}
+
+// replayBacklog handles "/backlog <seconds>" and
+// "/backlog <from_iso> <to_iso>", replaying matching stored messages
+// back to the requesting friend in chronological order.
+func replayBacklog(t *tox.Tox, bl *backlog.Store, friend uint32, pubKey string, cmd string) {
+	if bl == nil || pubKey == "" {
+		reply(t, bl, friend, pubKey, "backlog unavailable")
+		return
+	}
+
+	fields := strings.Fields(cmd)
+	var from, to time.Time
+	switch len(fields) {
+	case 2:
+		secs, err := strconv.Atoi(fields[1])
+		if err != nil {
+			reply(t, bl, friend, pubKey, "usage: /backlog <seconds> | /backlog <from_iso> <to_iso>")
+			return
+		}
+		to = time.Now()
+		from = to.Add(-time.Duration(secs) * time.Second)
+	case 3:
+		var err error
+		from, err = time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			reply(t, bl, friend, pubKey, "bad from timestamp: "+err.Error())
+			return
+		}
+		to, err = time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			reply(t, bl, friend, pubKey, "bad to timestamp: "+err.Error())
+			return
+		}
+	default:
+		reply(t, bl, friend, pubKey, "usage: /backlog <seconds> | /backlog <from_iso> <to_iso>")
+		return
+	}
+
+	entries, err := bl.Range(pubKey, from, to)
+	if err != nil {
+		reply(t, bl, friend, pubKey, "backlog query failed: "+err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		reply(t, bl, friend, pubKey, "no backlog messages in that range")
+		return
+	}
+
+	for _, e := range entries {
+		line := fmt.Sprintf("[%s] %s: %s", e.TS.Format(time.RFC3339), e.Direction, e.Body)
+		for _, chunk := range bridge.SplitMessage(line, bridge.ToxMaxMessageLength) {
+			_, _ = t.FriendSendMessage(friend, chunk)
+		}
+	}
+}
+
+// listInbox handles "/ls", replying with the files stored for this
+// friend.
+func listInbox(t *tox.Tox, ib *inbox.Manager, friend uint32, pubKey string) {
+	if pubKey == "" {
+		_, _ = t.FriendSendMessage(friend, "inbox unavailable")
+		return
+	}
+
+	files, err := ib.List(pubKey)
+	if err != nil {
+		_, _ = t.FriendSendMessage(friend, "ls failed: "+err.Error())
+		return
+	}
+	if len(files) == 0 {
+		_, _ = t.FriendSendMessage(friend, "inbox is empty")
+		return
+	}
+
+	var b strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&b, "%s (%d bytes)\n", f.Name, f.Size)
+	}
+	for _, chunk := range bridge.SplitMessage(strings.TrimRight(b.String(), "\n"), bridge.ToxMaxMessageLength) {
+		_, _ = t.FriendSendMessage(friend, chunk)
+	}
+}
+
+// sendFromInbox handles "/get <name>", streaming a previously stored
+// file back to the requesting friend through FileSend.
+func sendFromInbox(t *tox.Tox, ib *inbox.Manager, friend uint32, pubKey string, name string) {
+	name = strings.TrimSpace(name)
+	if pubKey == "" || name == "" {
+		_, _ = t.FriendSendMessage(friend, "usage: /get <name>")
+		return
+	}
+
+	size, err := ib.StatStored(pubKey, name)
+	if err != nil {
+		_, _ = t.FriendSendMessage(friend, "no such file: "+name)
+		return
+	}
+
+	fileNum, err := t.FileSend(friend, tox.FILE_KIND_DATA, uint64(size), "", name)
+	if err != nil {
+		_, _ = t.FriendSendMessage(friend, "get failed: "+err.Error())
+		return
+	}
+
+	_, err = ib.OpenForSend(friend, fileNum, pubKey, name)
+	if err != nil {
+		_, _ = t.FriendSendMessage(friend, "get failed: "+err.Error())
+		_ = t.FileControl(friend, fileNum, tox.FILE_CONTROL_CANCEL)
+		return
+	}
+	log.Printf("sending %q to %d (%d bytes)", name, friend, size)
+}
+
+// listGroups handles "/groups", replying with the conferences the bot
+// currently participates in.
+func listGroups(t *tox.Tox, conf *conference.Manager, friend uint32) {
+	groups := conf.List()
+	if len(groups) == 0 {
+		_, _ = t.FriendSendMessage(friend, "not in any conferences")
+		return
+	}
+
+	var b strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&b, "#%d %s\n", g.Number, g.Title)
+	}
+	for _, chunk := range bridge.SplitMessage(strings.TrimRight(b.String(), "\n"), bridge.ToxMaxMessageLength) {
+		_, _ = t.FriendSendMessage(friend, chunk)
+	}
+}
+
+// inviteToGroup handles "/invite <groupname>", inviting the requesting
+// friend to the named conference.
+func inviteToGroup(t *tox.Tox, conf *conference.Manager, friend uint32, name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		_, _ = t.FriendSendMessage(friend, "usage: /invite <groupname>")
+		return
+	}
+	if err := conf.Invite(friend, name); err != nil {
+		_, _ = t.FriendSendMessage(friend, "invite failed: "+err.Error())
+	}
+}
+
+// syncTox adapts *tox.Tox to bootstrap.Bootstrapper by running every
+// call through run (runOnTox), so the bootstrap manager's own goroutine
+// never touches libtoxcore directly.
+type syncTox struct {
+	t   *tox.Tox
+	run func(func())
+}
+
+func (s *syncTox) Bootstrap(host string, port uint16, pubKey string) (ok bool, err error) {
+	s.run(func() { ok, err = s.t.Bootstrap(host, port, pubKey) })
+	return ok, err
+}
+
+func (s *syncTox) AddTcpRelay(host string, port uint16, pubKey string) (ok bool, err error) {
+	s.run(func() { ok, err = s.t.AddTcpRelay(host, port, pubKey) })
+	return ok, err
+}
+
+func (s *syncTox) SelfGetConnectionStatus() (status int) {
+	s.run(func() { status = s.t.SelfGetConnectionStatus() })
+	return status
+}
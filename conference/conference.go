@@ -0,0 +1,247 @@
+// Package conference adds Tox conference (group chat) support: invites
+// are auto-accepted from an optional admin allow-list, conference
+// messages addressed to the bot are echoed back, and joined conferences
+// are recorded in a sidecar file next to the savedata so they show up
+// in /groups across restarts.
+//
+// Tox conferences themselves are not part of the account's savedata, so
+// surviving a restart means rejoining each one rather than just
+// restoring bookkeeping. The sidecar therefore also keeps the raw
+// invite cookie and the inviter's public key for every conference it
+// records; when that friend is next seen online, Manager replays the
+// cookie through ConferenceJoin to rejoin (see RejoinFriend). This still
+// requires the inviter to be reachable at that moment - a conference
+// whose only other member never comes back online cannot be rejoined.
+package conference
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Conferencer is the subset of *tox.Tox this package needs.
+type Conferencer interface {
+	ConferenceJoin(friend uint32, cookie []byte) (uint32, error)
+	ConferenceInvite(friend uint32, conference uint32) error
+	ConferenceSendMessage(conference uint32, message string) error
+	ConferenceGetTitle(conference uint32) (string, error)
+	ConferenceGetChatlist() []uint32
+}
+
+// Entry is one conference recorded in the sidecar file. Cookie and
+// InviterPubKey are kept so the conference can be rejoined after a
+// restart: ConferenceJoin accepts the same cookie again, and
+// InviterPubKey tells RejoinFriend which reconnecting friend to try it
+// against. Joined tracks whether this process has (re)joined the
+// conference; it is not persisted, since a fresh process has joined
+// nothing yet regardless of what the sidecar says.
+type Entry struct {
+	Title         string `json:"title"`
+	InviterPubKey string `json:"inviter_pub_key"`
+	Cookie        []byte `json:"cookie"`
+	Joined        bool   `json:"-"`
+}
+
+// cookieKey derives a stable map key from an invite cookie. Title can't
+// be used for this: ConferenceGetTitle returns "" until a title sync
+// completes after ConferenceJoin, so every freshly-joined conference
+// would otherwise collide under the same empty-string key.
+func cookieKey(cookie []byte) string {
+	return hex.EncodeToString(cookie)
+}
+
+// Manager auto-joins invited conferences, echoes addressed messages,
+// and persists a record of known conferences.
+type Manager struct {
+	t Conferencer
+
+	admins      map[string]bool
+	sidecarPath string
+	botName     string
+
+	mu      sync.Mutex
+	entries map[string]Entry // keyed by cookieKey, stable even before a title is synced
+}
+
+// NewManager builds a Manager. admins, if non-empty, restricts
+// auto-join to invites from those friend public keys. sidecarPath
+// stores known conferences as JSON next to the bot's savedata.
+func NewManager(t Conferencer, admins []string, sidecarPath string, botName string) *Manager {
+	m := &Manager{
+		t:           t,
+		admins:      make(map[string]bool, len(admins)),
+		sidecarPath: sidecarPath,
+		botName:     botName,
+		entries:     make(map[string]Entry),
+	}
+	for _, a := range admins {
+		m.admins[a] = true
+	}
+	return m
+}
+
+// Load restores known conferences from the sidecar file, if present.
+// It does not rejoin them itself; call RejoinFriend once an inviter
+// comes back online, or CallbackConferenceInviteAdd will add a fresh
+// entry if one arrives first.
+func (m *Manager) Load() error {
+	b, err := os.ReadFile(m.sidecarPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read conference sidecar: %w", err)
+	}
+
+	var stored map[string]Entry
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return fmt.Errorf("parse conference sidecar: %w", err)
+	}
+
+	m.mu.Lock()
+	m.entries = stored
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Manager) persist() error {
+	m.mu.Lock()
+	data, err := json.Marshal(m.entries)
+	m.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal conference sidecar: %w", err)
+	}
+	return os.WriteFile(m.sidecarPath, data, 0o600)
+}
+
+// AllowInvite reports whether a friend public key may trigger
+// auto-join. An empty admin list allows everyone.
+func (m *Manager) AllowInvite(friendPubKey string) bool {
+	if len(m.admins) == 0 {
+		return true
+	}
+	return m.admins[friendPubKey]
+}
+
+// HandleInvite accepts a conference invite and records it, keeping the
+// cookie and inviter so the conference can be rejoined later via
+// RejoinFriend. Call this from a CallbackConferenceInviteAdd handler
+// after AllowInvite passes.
+func (m *Manager) HandleInvite(friend uint32, inviterPubKey string, cookie []byte) (uint32, error) {
+	conf, err := m.t.ConferenceJoin(friend, cookie)
+	if err != nil {
+		return 0, fmt.Errorf("join conference: %w", err)
+	}
+
+	title, _ := m.t.ConferenceGetTitle(conf)
+
+	m.mu.Lock()
+	m.entries[cookieKey(cookie)] = Entry{Title: title, InviterPubKey: inviterPubKey, Cookie: cookie, Joined: true}
+	m.mu.Unlock()
+
+	if err := m.persist(); err != nil {
+		return conf, err
+	}
+	return conf, nil
+}
+
+// RejoinFriend replays the stored invite cookie for every conference
+// whose inviter was friendPubKey and that this process hasn't already
+// (re)joined, returning one error per failed rejoin. Call this from a
+// CallbackFriendConnectionStatusAdd handler when that friend comes
+// online, since ConferenceJoin needs them reachable to complete the
+// join.
+func (m *Manager) RejoinFriend(friend uint32, friendPubKey string) []error {
+	m.mu.Lock()
+	var keys []string
+	for key, e := range m.entries {
+		if e.InviterPubKey == friendPubKey && !e.Joined {
+			keys = append(keys, key)
+		}
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, key := range keys {
+		m.mu.Lock()
+		e := m.entries[key]
+		m.mu.Unlock()
+
+		if _, err := m.t.ConferenceJoin(friend, e.Cookie); err != nil {
+			errs = append(errs, fmt.Errorf("rejoin conference %q: %w", e.Title, err))
+			continue
+		}
+
+		m.mu.Lock()
+		e.Joined = true
+		m.entries[key] = e
+		m.mu.Unlock()
+	}
+	return errs
+}
+
+// HandleMessage echoes a conference message back if it is addressed to
+// the bot by name (e.g. "botname: /ping").
+func (m *Manager) HandleMessage(conference uint32, message string) {
+	prefix := m.botName + ":"
+	if len(message) <= len(prefix) || message[:len(prefix)] != prefix {
+		return
+	}
+
+	cmd := message[len(prefix):]
+	for len(cmd) > 0 && cmd[0] == ' ' {
+		cmd = cmd[1:]
+	}
+
+	_ = m.t.ConferenceSendMessage(conference, "echo: "+cmd)
+}
+
+// SendByTitle sends text to the joined conference matching name. It is
+// used by the bridge fabric to deliver external messages into a
+// conference addressed as "conference:<name>".
+func (m *Manager) SendByTitle(name string, text string) error {
+	for _, conf := range m.t.ConferenceGetChatlist() {
+		title, err := m.t.ConferenceGetTitle(conf)
+		if err != nil {
+			continue
+		}
+		if title == name {
+			return m.t.ConferenceSendMessage(conf, text)
+		}
+	}
+	return fmt.Errorf("no such conference: %s", name)
+}
+
+// GroupInfo describes one conference the bot participates in.
+type GroupInfo struct {
+	Number uint32
+	Title  string
+}
+
+// List returns every conference the bot currently participates in.
+func (m *Manager) List() []GroupInfo {
+	var out []GroupInfo
+	for _, conf := range m.t.ConferenceGetChatlist() {
+		title, _ := m.t.ConferenceGetTitle(conf)
+		out = append(out, GroupInfo{Number: conf, Title: title})
+	}
+	return out
+}
+
+// Invite sends an invite to the named conference to friend, matching by
+// title. It returns an error if no conference with that name is joined.
+func (m *Manager) Invite(friend uint32, name string) error {
+	for _, conf := range m.t.ConferenceGetChatlist() {
+		title, err := m.t.ConferenceGetTitle(conf)
+		if err != nil {
+			continue
+		}
+		if title == name {
+			return m.t.ConferenceInvite(friend, conf)
+		}
+	}
+	return fmt.Errorf("no such conference: %s", name)
+}
@@ -0,0 +1,151 @@
+package conference
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+type fakeConferencer struct {
+	nextConf uint32
+	titles   map[uint32]string
+	joined   []uint32
+	untitled bool // joined conferences report "" as their title
+}
+
+func (f *fakeConferencer) ConferenceJoin(friend uint32, cookie []byte) (uint32, error) {
+	f.nextConf++
+	conf := f.nextConf
+	title := string(cookie)
+	if f.untitled {
+		title = ""
+	}
+	f.titles[conf] = title
+	f.joined = append(f.joined, conf)
+	return conf, nil
+}
+
+func (f *fakeConferencer) ConferenceInvite(friend uint32, conference uint32) error { return nil }
+func (f *fakeConferencer) ConferenceSendMessage(conference uint32, message string) error {
+	return nil
+}
+
+func (f *fakeConferencer) ConferenceGetTitle(conference uint32) (string, error) {
+	title, ok := f.titles[conference]
+	if !ok {
+		return "", fmt.Errorf("no such conference: %d", conference)
+	}
+	return title, nil
+}
+
+func (f *fakeConferencer) ConferenceGetChatlist() []uint32 {
+	return f.joined
+}
+
+func newFakeConferencer() *fakeConferencer {
+	return &fakeConferencer{titles: make(map[uint32]string)}
+}
+
+func TestHandleInvitePersistsCookieAndInviter(t *testing.T) {
+	fc := newFakeConferencer()
+	m := NewManager(fc, nil, filepath.Join(t.TempDir(), "conferences.json"), "bot")
+
+	conf, err := m.HandleInvite(1, "inviterkey", []byte("my-conference"))
+	if err != nil {
+		t.Fatalf("HandleInvite: %v", err)
+	}
+	if conf != 1 {
+		t.Fatalf("conf = %d, want 1", conf)
+	}
+
+	key := hex.EncodeToString([]byte("my-conference"))
+	m.mu.Lock()
+	e, ok := m.entries[key]
+	m.mu.Unlock()
+	if !ok {
+		t.Fatalf("entries missing %q: %#v", key, m.entries)
+	}
+	if e.InviterPubKey != "inviterkey" || string(e.Cookie) != "my-conference" {
+		t.Errorf("entry = %#v, want inviter %q cookie %q", e, "inviterkey", "my-conference")
+	}
+}
+
+func TestHandleInviteDoesNotCollideOnEmptyTitle(t *testing.T) {
+	fc := newFakeConferencer()
+	fc.untitled = true
+	m := NewManager(fc, nil, filepath.Join(t.TempDir(), "conferences.json"), "bot")
+
+	// ConferenceGetTitle returns "" for both until a title sync lands, as
+	// it does in practice right after ConferenceJoin.
+	if _, err := m.HandleInvite(1, "inviterkey", []byte("cookie-a")); err != nil {
+		t.Fatalf("HandleInvite: %v", err)
+	}
+	if _, err := m.HandleInvite(1, "inviterkey", []byte("cookie-b")); err != nil {
+		t.Fatalf("HandleInvite: %v", err)
+	}
+
+	m.mu.Lock()
+	n := len(m.entries)
+	m.mu.Unlock()
+	if n != 2 {
+		t.Errorf("len(entries) = %d, want 2 (second untitled invite must not overwrite the first)", n)
+	}
+}
+
+func TestRejoinFriendReplaysCookieAfterRestart(t *testing.T) {
+	sidecar := filepath.Join(t.TempDir(), "conferences.json")
+
+	fc := newFakeConferencer()
+	m := NewManager(fc, nil, sidecar, "bot")
+	if _, err := m.HandleInvite(1, "inviterkey", []byte("group-a")); err != nil {
+		t.Fatalf("HandleInvite: %v", err)
+	}
+
+	// Simulate a restart: a fresh Manager loads the sidecar, so Joined
+	// starts false even though the original Manager already joined.
+	fc2 := newFakeConferencer()
+	m2 := NewManager(fc2, nil, sidecar, "bot")
+	if err := m2.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if errs := m2.RejoinFriend(1, "inviterkey"); len(errs) != 0 {
+		t.Fatalf("RejoinFriend errs = %v, want none", errs)
+	}
+	if len(fc2.joined) != 1 {
+		t.Fatalf("ConferenceJoin was not replayed, joined = %#v", fc2.joined)
+	}
+
+	// A second reconnect from the same friend should not rejoin what
+	// this process already (re)joined.
+	if errs := m2.RejoinFriend(1, "inviterkey"); len(errs) != 0 {
+		t.Fatalf("RejoinFriend errs = %v, want none", errs)
+	}
+	if len(fc2.joined) != 1 {
+		t.Errorf("RejoinFriend rejoined an already-joined conference, joined = %#v", fc2.joined)
+	}
+}
+
+func TestRejoinFriendIgnoresOtherInviters(t *testing.T) {
+	sidecar := filepath.Join(t.TempDir(), "conferences.json")
+
+	fc := newFakeConferencer()
+	m := NewManager(fc, nil, sidecar, "bot")
+	if _, err := m.HandleInvite(1, "inviterkey", []byte("group-a")); err != nil {
+		t.Fatalf("HandleInvite: %v", err)
+	}
+
+	fc2 := newFakeConferencer()
+	m2 := NewManager(fc2, nil, sidecar, "bot")
+	if err := m2.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if errs := m2.RejoinFriend(2, "someoneelse"); len(errs) != 0 {
+		t.Fatalf("RejoinFriend errs = %v, want none", errs)
+	}
+	if len(fc2.joined) != 0 {
+		t.Errorf("RejoinFriend joined on unrelated friend, joined = %#v", fc2.joined)
+	}
+}
@@ -0,0 +1,208 @@
+// Package control exposes the bot's Tox state as a small record
+// dataspace over a Unix socket, inspired by the syndicate_actor_tox
+// protocol: connected agents receive a stream of JSON-lines assertions
+// about friends and connection state, and can send back commands
+// without linking against go-toxcore-c themselves.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"sync"
+)
+
+// Record is one assertion or command line on the wire. Type tags the
+// payload so untyped JSON decoders can dispatch on it.
+type Record struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Assertion type tags published by the bot.
+const (
+	TypeSelfAddress          = "ToxSelfAddress"
+	TypeSelfConnectionStatus = "ToxSelfConnectionStatus"
+	TypeFriendKey            = "ToxFriendKey"
+	TypeFriendName           = "ToxFriendName"
+	TypeFriendStatusMessage  = "ToxFriendStatusMessage"
+	TypeFriendLastOnline     = "ToxFriendLastOnline"
+	TypeFriendTyping         = "ToxFriendTyping"
+)
+
+// Command type tags accepted from connected agents.
+const (
+	TypeFriendAdd     = "ToxFriendAdd"
+	TypeFriendSend    = "ToxFriendSend"
+	TypeSelfSetName   = "ToxSelfSetName"
+	TypeSelfSetStatus = "ToxSelfSetStatus"
+)
+
+// FriendAddCmd asks the bot to send a friend request.
+type FriendAddCmd struct {
+	Address string `json:"address"`
+	Message string `json:"message"`
+}
+
+// FriendSendCmd asks the bot to message an existing friend.
+type FriendSendCmd struct {
+	Num     uint32 `json:"num"`
+	Message string `json:"message"`
+}
+
+// SelfSetNameCmd asks the bot to change its display name.
+type SelfSetNameCmd struct {
+	Name string `json:"name"`
+}
+
+// SelfSetStatusCmd asks the bot to change its status message.
+type SelfSetStatusCmd struct {
+	Status string `json:"status"`
+}
+
+// Handlers dispatches commands received over the socket into the Tox
+// instance; each field is optional and ignored if nil.
+type Handlers struct {
+	OnFriendAdd     func(cmd FriendAddCmd) error
+	OnFriendSend    func(cmd FriendSendCmd) error
+	OnSelfSetName   func(cmd SelfSetNameCmd) error
+	OnSelfSetStatus func(cmd SelfSetStatusCmd) error
+}
+
+// Server accepts agent connections on a Unix socket, publishing
+// assertions to every connected agent and dispatching commands it reads
+// back to Handlers.
+type Server struct {
+	ln       net.Listener
+	handlers Handlers
+
+	mu      sync.Mutex
+	writers map[net.Conn]*json.Encoder
+}
+
+// Listen creates the control socket at path, removing any stale socket
+// left behind by a previous run.
+func Listen(path string, handlers Handlers) (*Server, error) {
+	_ = os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ln:       ln,
+		handlers: handlers,
+		writers:  make(map[net.Conn]*json.Encoder),
+	}
+	go s.acceptLoop()
+	return s, nil
+}
+
+// Close stops accepting connections and closes the socket.
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.writers[conn] = json.NewEncoder(conn)
+		s.mu.Unlock()
+		go s.readLoop(conn)
+	}
+}
+
+func (s *Server) readLoop(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.writers, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			log.Printf("control: bad record: %v", err)
+			continue
+		}
+		if err := s.dispatch(rec); err != nil {
+			log.Printf("control: dispatch %s failed: %v", rec.Type, err)
+		}
+	}
+}
+
+func (s *Server) dispatch(rec Record) error {
+	switch rec.Type {
+	case TypeFriendAdd:
+		if s.handlers.OnFriendAdd == nil {
+			return nil
+		}
+		var cmd FriendAddCmd
+		if err := json.Unmarshal(rec.Data, &cmd); err != nil {
+			return err
+		}
+		return s.handlers.OnFriendAdd(cmd)
+
+	case TypeFriendSend:
+		if s.handlers.OnFriendSend == nil {
+			return nil
+		}
+		var cmd FriendSendCmd
+		if err := json.Unmarshal(rec.Data, &cmd); err != nil {
+			return err
+		}
+		return s.handlers.OnFriendSend(cmd)
+
+	case TypeSelfSetName:
+		if s.handlers.OnSelfSetName == nil {
+			return nil
+		}
+		var cmd SelfSetNameCmd
+		if err := json.Unmarshal(rec.Data, &cmd); err != nil {
+			return err
+		}
+		return s.handlers.OnSelfSetName(cmd)
+
+	case TypeSelfSetStatus:
+		if s.handlers.OnSelfSetStatus == nil {
+			return nil
+		}
+		var cmd SelfSetStatusCmd
+		if err := json.Unmarshal(rec.Data, &cmd); err != nil {
+			return err
+		}
+		return s.handlers.OnSelfSetStatus(cmd)
+
+	default:
+		log.Printf("control: unknown record type %q", rec.Type)
+		return nil
+	}
+}
+
+// Publish encodes data as a Record with the given type tag and
+// broadcasts it to every connected agent.
+func (s *Server) Publish(typ string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("control: marshal %s failed: %v", typ, err)
+		return
+	}
+	rec := Record{Type: typ, Data: payload}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn, enc := range s.writers {
+		if err := enc.Encode(rec); err != nil {
+			log.Printf("control: publish to %s failed: %v", conn.RemoteAddr(), err)
+		}
+	}
+}
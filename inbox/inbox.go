@@ -0,0 +1,267 @@
+// Package inbox turns the bot into a small per-friend dropbox: incoming
+// file transfers are streamed to disk under a friend's own directory
+// with resume support, and stored files can be listed and sent back
+// through the Tox file-transfer API.
+package inbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// xferKey identifies one in-flight transfer.
+type xferKey struct {
+	friend  uint32
+	fileNum uint32
+}
+
+type recvState struct {
+	file     *os.File
+	path     string
+	size     uint64
+	received uint64
+}
+
+type sendState struct {
+	file *os.File
+	size uint64
+}
+
+// Manager tracks in-flight file transfers and enforces a per-friend
+// storage quota under baseDir.
+type Manager struct {
+	baseDir  string
+	maxBytes int64
+
+	mu   sync.Mutex
+	recv map[xferKey]*recvState
+	send map[xferKey]*sendState
+}
+
+// NewManager creates a Manager rooted at baseDir (typically
+// ${TOX_DATA_DIR}/inbox). maxBytes <= 0 means no quota.
+func NewManager(baseDir string, maxBytes int64) *Manager {
+	return &Manager{
+		baseDir:  baseDir,
+		maxBytes: maxBytes,
+		recv:     make(map[xferKey]*recvState),
+		send:     make(map[xferKey]*sendState),
+	}
+}
+
+func (m *Manager) friendDir(pubKey string) string {
+	return filepath.Join(m.baseDir, pubKey)
+}
+
+// usage returns the total size in bytes of everything already stored
+// for pubKey.
+func (m *Manager) usage(pubKey string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(m.friendDir(pubKey), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// Accept decides whether an incoming transfer fits the friend's quota
+// and, if so, opens (or resumes) the destination file. It returns the
+// byte offset the transfer should resume from, which is 0 for a fresh
+// file.
+func (m *Manager) Accept(friend uint32, fileNum uint32, pubKey string, fileSize uint64, fileName string) (resumeAt uint64, err error) {
+	dir := m.friendDir(pubKey)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("mkdir inbox dir: %w", err)
+	}
+
+	if m.maxBytes > 0 {
+		used, err := m.usage(pubKey)
+		if err != nil {
+			return 0, fmt.Errorf("check inbox quota: %w", err)
+		}
+		if used+int64(fileSize) > m.maxBytes {
+			return 0, fmt.Errorf("inbox quota exceeded (%d + %d > %d bytes)", used, fileSize, m.maxBytes)
+		}
+	}
+
+	path := filepath.Join(dir, filepath.Base(fileName))
+
+	var f *os.File
+	var offset uint64
+	if info, statErr := os.Stat(path + ".part"); statErr == nil && uint64(info.Size()) < fileSize {
+		f, err = os.OpenFile(path+".part", os.O_WRONLY, 0o644)
+		offset = uint64(info.Size())
+	} else {
+		f, err = os.Create(path + ".part")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("open inbox file: %w", err)
+	}
+
+	m.mu.Lock()
+	m.recv[xferKey{friend, fileNum}] = &recvState{file: f, path: path, size: fileSize, received: offset}
+	m.mu.Unlock()
+
+	return offset, nil
+}
+
+// WriteChunk stores one received chunk at position. An empty chunk
+// marks the transfer complete and finalizes the file.
+func (m *Manager) WriteChunk(friend uint32, fileNum uint32, position uint64, data []byte) error {
+	key := xferKey{friend, fileNum}
+
+	m.mu.Lock()
+	st, ok := m.recv[key]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no in-flight receive for friend %d file %d", friend, fileNum)
+	}
+
+	if len(data) == 0 {
+		return m.finishRecv(key, st)
+	}
+
+	if _, err := st.file.WriteAt(data, int64(position)); err != nil {
+		return fmt.Errorf("write inbox chunk: %w", err)
+	}
+	st.received = position + uint64(len(data))
+	return nil
+}
+
+func (m *Manager) finishRecv(key xferKey, st *recvState) error {
+	if err := st.file.Close(); err != nil {
+		return fmt.Errorf("close inbox file: %w", err)
+	}
+	if err := os.Rename(st.path+".part", st.path); err != nil {
+		return fmt.Errorf("finalize inbox file: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.recv, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// AbortRecv drops an in-flight receive without finalizing the file, so
+// a later transfer with the same name can resume from what was saved.
+func (m *Manager) AbortRecv(friend uint32, fileNum uint32) {
+	key := xferKey{friend, fileNum}
+
+	m.mu.Lock()
+	st, ok := m.recv[key]
+	delete(m.recv, key)
+	m.mu.Unlock()
+
+	if ok {
+		_ = st.file.Close()
+	}
+}
+
+// FileInfo describes one file stored for a friend.
+type FileInfo struct {
+	Name string
+	Size int64
+}
+
+// List returns the files stored for pubKey, sorted by name.
+func (m *Manager) List(pubKey string) ([]FileInfo, error) {
+	entries, err := os.ReadDir(m.friendDir(pubKey))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []FileInfo
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) == ".part" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, FileInfo{Name: e.Name(), Size: info.Size()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// StatStored returns the size of a stored file, for declaring it in
+// the FileSend call before OpenForSend registers the transfer.
+func (m *Manager) StatStored(pubKey string, name string) (int64, error) {
+	path := filepath.Join(m.friendDir(pubKey), filepath.Base(name))
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("stat stored file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// OpenForSend opens a stored file to begin sending it back to the
+// friend once FileSend has assigned fileNum.
+func (m *Manager) OpenForSend(friend uint32, fileNum uint32, pubKey string, name string) (size uint64, err error) {
+	path := filepath.Join(m.friendDir(pubKey), filepath.Base(name))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open stored file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("stat stored file: %w", err)
+	}
+
+	m.mu.Lock()
+	m.send[xferKey{friend, fileNum}] = &sendState{file: f, size: uint64(info.Size())}
+	m.mu.Unlock()
+
+	return uint64(info.Size()), nil
+}
+
+// ReadChunk returns length bytes at position for an outbound transfer,
+// for use from a CallbackFileChunkRequestAdd handler. An empty slice
+// signals the transfer is complete and releases the open file.
+func (m *Manager) ReadChunk(friend uint32, fileNum uint32, position uint64, length int) ([]byte, error) {
+	key := xferKey{friend, fileNum}
+
+	m.mu.Lock()
+	st, ok := m.send[key]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no in-flight send for friend %d file %d", friend, fileNum)
+	}
+
+	if length == 0 || position >= st.size {
+		m.mu.Lock()
+		delete(m.send, key)
+		m.mu.Unlock()
+		_ = st.file.Close()
+		return nil, nil
+	}
+
+	buf := make([]byte, length)
+	n, err := st.file.ReadAt(buf, int64(position))
+	if err != nil && n == 0 {
+		return nil, fmt.Errorf("read stored file: %w", err)
+	}
+	return buf[:n], nil
+}